@@ -0,0 +1,101 @@
+// Package store implements a small persistent key/value store used to
+// remember which releases have already been processed, so that repeated
+// filesystem events or rescans don't redo the same work.
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const dirName = "unp"
+
+// entry records when a key was last seen.
+type entry struct {
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Store is a JSON file backed key/value store, safe for concurrent use.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+// DefaultPath returns the default path of the store file, rooted at
+// $XDG_CACHE_HOME, falling back to $HOME/.cache if unset.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, dirName, "store.json"), nil
+}
+
+// New reads the Store persisted at path, or creates an empty one if path
+// does not yet exist.
+func New(path string) (*Store, error) {
+	s := &Store{path: path, entries: make(map[string]entry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	return s, nil
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// Seen reports whether key was recorded less than ttl ago.
+func (s *Store) Seen(key string, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return false
+	}
+	return time.Since(e.Timestamp) < ttl
+}
+
+// Record marks key as processed now, persisting the store to disk.
+func (s *Store) Record(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry{Timestamp: time.Now()}
+	return s.save()
+}
+
+// Forget removes key from the store, if present, persisting the store to
+// disk.
+func (s *Store) Forget(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[key]; !ok {
+		return errors.Errorf("key not found: %s", key)
+	}
+	delete(s.entries, key)
+	return s.save()
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSeenRecordForget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const key = "abc123"
+	if s.Seen(key, time.Hour) {
+		t.Fatal("key not yet recorded should not be seen")
+	}
+	if err := s.Record(key); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Seen(key, time.Hour) {
+		t.Fatal("key recorded within ttl should be seen")
+	}
+	if s.Seen(key, 0) {
+		t.Fatal("key should not be seen with a zero ttl")
+	}
+	if err := s.Forget(key); err != nil {
+		t.Fatal(err)
+	}
+	if s.Seen(key, time.Hour) {
+		t.Fatal("forgotten key should not be seen")
+	}
+	if err := s.Forget(key); err == nil {
+		t.Fatal("expected Forget of an already-forgotten key to fail")
+	}
+}
+
+func TestNewReadsPersistedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s1, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const key = "def456"
+	if err := s1.Record(key); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s2.Seen(key, time.Hour) {
+		t.Fatal("expected key recorded by a prior Store to be seen after reloading from path")
+	}
+}
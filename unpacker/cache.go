@@ -0,0 +1,219 @@
+package unpacker
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mpolden/unp/store"
+	"github.com/mpolden/unp/watcher"
+	"github.com/pkg/errors"
+)
+
+const defaultPruneInterval = time.Minute
+
+// Handler verifies and unpacks releases reported by a watcher. It caches
+// per-file verification results across events so that repeated writes to
+// the same release only re-verify the file that actually changed, instead
+// of re-hashing every file in the SFV on every event.
+type Handler struct {
+	mu       sync.Mutex
+	verified map[string]bool
+	interval time.Duration
+	done     chan bool
+	log      *log.Logger
+
+	store *store.Store
+	ttl   time.Duration
+}
+
+// UseStore makes h consult store before unpacking a release, skipping
+// releases whose content key was recorded less than ttl ago, and records
+// the content key of every release h successfully unpacks.
+func (h *Handler) UseStore(s *store.Store, ttl time.Duration) {
+	h.store = s
+	h.ttl = ttl
+}
+
+// SetLogger makes h log non-fatal errors, such as a failed store.Record
+// after a release has already been unpacked, to l instead of the standard
+// logger.
+func (h *Handler) SetLogger(l *log.Logger) {
+	h.log = l
+}
+
+// logger returns the logger to use for non-fatal errors, falling back to
+// the standard logger if SetLogger was never called.
+func (h *Handler) logger() *log.Logger {
+	if h.log != nil {
+		return h.log
+	}
+	return log.Default()
+}
+
+// NewHandler returns a Handler that prunes stale cache entries once per
+// minute.
+func NewHandler() *Handler { return NewHandlerWithInterval(defaultPruneInterval) }
+
+// NewHandlerWithInterval returns a Handler that prunes cache entries whose
+// files no longer exist every d.
+func NewHandlerWithInterval(d time.Duration) *Handler {
+	h := &Handler{
+		verified: make(map[string]bool),
+		interval: d,
+		done:     make(chan bool, 1),
+	}
+	go h.prune()
+	return h
+}
+
+// prune periodically removes cache entries for files that have since been
+// removed, e.g. by a completed unpack.
+func (h *Handler) prune() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			h.mu.Lock()
+			for path := range h.verified {
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					delete(h.verified, path)
+				}
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+// Stop stops the background pruner. It must be called when the Handler is
+// no longer needed.
+func (h *Handler) Stop() { h.done <- true }
+
+// verify verifies the checksum of the single entry in u.SFV matching the
+// basename of name, caching and returning the result.
+func (h *Handler) verify(u *unpacker, name string) (bool, error) {
+	base := filepath.Base(name)
+	for _, c := range u.SFV.Checksums {
+		if filepath.Base(c.Path) != base {
+			continue
+		}
+		h.mu.Lock()
+		ok, cached := h.verified[c.Path]
+		h.mu.Unlock()
+		if cached && ok {
+			return true, nil
+		}
+		ok, err := c.Verify()
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			watcher.ObserveVerifyFailure()
+		}
+		h.mu.Lock()
+		h.verified[c.Path] = ok
+		h.mu.Unlock()
+		return ok, nil
+	}
+	return false, errors.Errorf("%s: not found in %s", base, u.SFV.Path)
+}
+
+// CachedVerifiedFiles returns the number of files currently recorded as
+// verified in h's cache. It implements watcher.MetricsReporter.
+func (h *Handler) CachedVerifiedFiles() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, ok := range h.verified {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// allVerified reports whether every checksum entry in u.SFV is recorded as
+// verified in the cache.
+func (h *Handler) allVerified(u *unpacker) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range u.SFV.Checksums {
+		if !h.verified[c.Path] {
+			return false
+		}
+	}
+	return true
+}
+
+// forget removes all cache entries belonging to u.SFV, e.g. after a
+// successful unpack has consumed them.
+func (h *Handler) forget(u *unpacker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range u.SFV.Checksums {
+		delete(h.verified, c.Path)
+	}
+}
+
+// Handle implements the watcher.OnFile signature. It verifies the file at
+// name against its entry in the containing release's SFV, and only
+// proceeds to unpack once every file in that SFV has been verified.
+func (h *Handler) Handle(name, postCommand string, remove bool) error {
+	u, err := New(filepath.Dir(name))
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize unpacker")
+	}
+	if exists, total := u.fileCount(); exists != total {
+		return nil
+	}
+	key := contentKey(u.SFV)
+	if h.store != nil && h.store.Seen(key, h.ttl) {
+		return nil
+	}
+	ok, err := h.verify(u, name)
+	if err != nil {
+		return errors.Wrapf(err, "verification of %s failed", name)
+	}
+	if !ok || !h.allVerified(u) {
+		return nil
+	}
+	if err := u.unpack(u.Name); err != nil {
+		return errors.Wrapf(err, "unpacking %s failed", u.Dir)
+	}
+	if info, err := os.Stat(u.Name); err == nil {
+		watcher.ObserveArchiveSize(info.Size())
+	}
+	if remove {
+		if err := u.remove(); err != nil {
+			return errors.Wrapf(err, "cleaning up %s failed", u.Dir)
+		}
+	}
+	h.forget(u)
+	if h.store != nil {
+		// The release is already unpacked (and possibly removed) at this
+		// point, so a failure to record it is not a pipeline failure: log
+		// it and continue, rather than reporting the unpack as failed.
+		if err := h.store.Record(key); err != nil {
+			h.logger().Printf("Failed to record processed release %s: %s", u.Dir, err)
+		}
+	}
+	if err := postProcess(u, postCommand); err != nil {
+		return errors.Wrap(err, "post-process command failed")
+	}
+	return nil
+}
+
+// Forget removes the cache entry for the release found in dir from s. It
+// backs the `unp --forget <dir>` command.
+func Forget(dir string, s *store.Store) error {
+	u, err := New(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize unpacker")
+	}
+	return s.Forget(contentKey(u.SFV))
+}
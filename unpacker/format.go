@@ -0,0 +1,104 @@
+package unpacker
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+
+	"github.com/mpolden/sfv"
+	"github.com/pkg/errors"
+)
+
+// Format identifies a supported archive format.
+type Format int
+
+const (
+	// FormatRAR is a RAR archive, optionally split into multiple volumes
+	// (foo.part01.rar, foo.part02.rar, ...).
+	FormatRAR Format = iota
+	// FormatZip is a ZIP archive, optionally split into multiple volumes
+	// (foo.zip.001, foo.zip.002, ...).
+	FormatZip
+	// Format7z is a 7-Zip archive, optionally split into multiple volumes
+	// (foo.7z.001, foo.7z.002, ...).
+	Format7z
+	// FormatTarGz is a gzip-compressed tar archive.
+	FormatTarGz
+	// FormatTarBz2 is a bzip2-compressed tar archive.
+	FormatTarBz2
+	// FormatTarXz is an xz-compressed tar archive.
+	FormatTarXz
+)
+
+var extRE = map[Format]*regexp.Regexp{
+	FormatRAR:    regexp.MustCompile(`\.(?:part0*\d+\.)?rar$`),
+	FormatZip:    regexp.MustCompile(`\.zip(?:\.\d+)?$`),
+	Format7z:     regexp.MustCompile(`\.7z(?:\.\d+)?$`),
+	FormatTarGz:  regexp.MustCompile(`\.(?:tar\.gz|tgz)$`),
+	FormatTarBz2: regexp.MustCompile(`\.(?:tar\.bz2|tbz2)$`),
+	FormatTarXz:  regexp.MustCompile(`\.(?:tar\.xz|txz)$`),
+}
+
+// magic holds the leading bytes used to identify a Format when its file
+// extension is missing or has been stripped.
+var magic = map[Format][]byte{
+	FormatRAR:    []byte("Rar!\x1a\x07"),
+	FormatZip:    []byte("PK\x03\x04"),
+	Format7z:     []byte("7z\xbc\xaf\x27\x1c"),
+	FormatTarGz:  []byte("\x1f\x8b"),
+	FormatTarBz2: []byte("BZh"),
+	FormatTarXz:  []byte("\xfd7zXZ\x00"),
+}
+
+// formatOf returns the Format of name, determined from its extension. ok is
+// false if name does not match any known extension.
+func formatOf(name string) (format Format, ok bool) {
+	for f, re := range extRE {
+		if re.MatchString(name) {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// sniff returns the Format of name determined from its magic bytes. ok is
+// false if name could not be opened or does not match a known signature.
+func sniff(name string) (format Format, ok bool) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	buf := make([]byte, 8)
+	n, _ := f.Read(buf)
+	buf = buf[:n]
+	for format, sig := range magic {
+		if bytes.HasPrefix(buf, sig) {
+			return format, true
+		}
+	}
+	return 0, false
+}
+
+// detectFormat returns the Format and handler responsible for the archive
+// described by s, along with the name of its first volume. Detection tries
+// the file extension of each checksum first, falling back to magic bytes.
+func detectFormat(s *sfv.SFV) (Format, FormatHandler, string, error) {
+	for _, c := range s.Checksums {
+		if format, ok := formatOf(c.Path); ok {
+			h := handlers[format]
+			if name, ok := h.FirstVolume(s); ok {
+				return format, h, name, nil
+			}
+		}
+	}
+	for _, c := range s.Checksums {
+		if format, ok := sniff(c.Path); ok {
+			h := handlers[format]
+			if name, ok := h.FirstVolume(s); ok {
+				return format, h, name, nil
+			}
+		}
+	}
+	return 0, nil, "", errors.Errorf("no supported archive found in %s", s.Path)
+}
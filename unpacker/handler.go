@@ -0,0 +1,51 @@
+package unpacker
+
+import (
+	"os"
+	"time"
+
+	"github.com/mpolden/sfv"
+)
+
+// entry describes a single file or directory extracted from an archive, in
+// a form common to all supported formats.
+type entry struct {
+	Name    string
+	IsDir   bool
+	Mode    os.FileMode
+	ModTime time.Time
+	// Linkname is the target of a symlink (Mode&os.ModeSymlink != 0) or
+	// hardlink (Hardlink true) entry.
+	Linkname string
+	Hardlink bool
+}
+
+// archiveReader iterates over the entries of an open archive. Read reads
+// from the content of the entry most recently returned by Next.
+type archiveReader interface {
+	Next() (entry, error)
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+// FormatHandler implements detection and extraction for a single archive
+// Format.
+type FormatHandler interface {
+	// FirstVolume returns the name of the first volume of the archive
+	// described by s. ok is false if s does not contain a file of this
+	// FormatHandler's Format.
+	FirstVolume(s *sfv.SFV) (name string, ok bool)
+	// Open opens name, the first volume of the archive, for sequential
+	// reading of its entries.
+	Open(name string) (archiveReader, error)
+}
+
+// handlers maps each supported Format to the FormatHandler responsible for it.
+var handlers = map[Format]FormatHandler{
+	FormatRAR:    rarHandler{},
+	FormatZip:    zipHandler{},
+	Format7z:     sevenZipHandler{},
+	FormatTarGz:  tarHandler{format: FormatTarGz, decompress: gzipReader},
+	FormatTarBz2: tarHandler{format: FormatTarBz2, decompress: bzip2Reader},
+	FormatTarXz:  tarHandler{format: FormatTarXz, decompress: xzReader},
+}
@@ -0,0 +1,22 @@
+package unpacker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/mpolden/sfv"
+)
+
+// contentKey returns a stable identifier for the release described by s,
+// derived from the SHA-256 of the file names and CRCs of its checksums, in
+// SFV order. Two directories with the same SFV entries, e.g. after being
+// moved or re-downloaded, hash to the same key.
+func contentKey(s *sfv.SFV) string {
+	h := sha256.New()
+	for _, c := range s.Checksums {
+		io.WriteString(h, c.Filename)
+		io.WriteString(h, c.CRC)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
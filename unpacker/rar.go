@@ -0,0 +1,60 @@
+package unpacker
+
+import (
+	"io"
+	"regexp"
+
+	"github.com/mpolden/sfv"
+	"github.com/nwaples/rardecode"
+	"github.com/pkg/errors"
+)
+
+var rarPartRE = regexp.MustCompile(`\.part0*(\d+)\.rar$`)
+
+func isRAR(name string) bool { return extRE[FormatRAR].MatchString(name) }
+
+func isFirstRAR(name string) bool {
+	m := rarPartRE.FindStringSubmatch(name)
+	if len(m) == 2 {
+		return m[1] == "1"
+	}
+	return isRAR(name)
+}
+
+// rarHandler implements FormatHandler for FormatRAR, using rardecode.
+type rarHandler struct{}
+
+func (rarHandler) FirstVolume(s *sfv.SFV) (string, bool) {
+	for _, c := range s.Checksums {
+		if isFirstRAR(c.Path) {
+			return c.Path, true
+		}
+	}
+	return "", false
+}
+
+func (rarHandler) Open(name string) (archiveReader, error) {
+	r, err := rardecode.OpenReader(name, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", name)
+	}
+	return &rarReader{r: r}, nil
+}
+
+type rarReader struct {
+	r *rardecode.ReadCloser
+}
+
+func (rr *rarReader) Next() (entry, error) {
+	h, err := rr.r.Next()
+	if err != nil {
+		return entry{}, err
+	}
+	return entry{Name: h.Name, IsDir: h.IsDir, Mode: h.Mode(), ModTime: h.ModificationTime}, nil
+}
+
+func (rr *rarReader) Read(p []byte) (int, error) { return rr.r.Read(p) }
+
+func (rr *rarReader) Close() error { return rr.r.Close() }
+
+var _ io.Closer = (*rarReader)(nil)
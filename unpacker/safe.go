@@ -0,0 +1,46 @@
+package unpacker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// containedPath joins root and name, and verifies that the result does not
+// escape root, rejecting archive entries such as "../../etc/passwd" or
+// absolute paths.
+func containedPath(root, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", errors.Errorf("refusing to extract %q: absolute path", name)
+	}
+	joined := filepath.Clean(filepath.Join(root, name))
+	if joined != root && !strings.HasPrefix(joined, root+string(filepath.Separator)) {
+		return "", errors.Errorf("refusing to extract %q: escapes %s", name, root)
+	}
+	return joined, nil
+}
+
+// checkSymlink verifies that a symlink at path, with the given target,
+// would not resolve to somewhere outside root.
+func checkSymlink(root, path, target string) error {
+	if filepath.IsAbs(target) {
+		return errors.Errorf("refusing to create symlink %q: absolute target %q", path, target)
+	}
+	resolved := filepath.Clean(filepath.Join(filepath.Dir(path), target))
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return errors.Errorf("refusing to create symlink %q: target %q escapes %s", path, target, root)
+	}
+	return nil
+}
+
+// checkHardlink verifies that a hardlink at path, pointing at target
+// (relative to root), would not resolve to somewhere outside root.
+func checkHardlink(root, path, target string) (string, error) {
+	resolved, err := containedPath(root, target)
+	if err != nil {
+		return "", errors.Errorf("refusing to create hardlink %q: %s", path, err)
+	}
+	return resolved, nil
+}
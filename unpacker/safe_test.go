@@ -0,0 +1,61 @@
+package unpacker
+
+import "testing"
+
+func TestContainedPath(t *testing.T) {
+	root := "/tmp/release"
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"foo.txt", false},
+		{"sub/dir/foo.txt", false},
+		{"../../etc/passwd", true},
+		{"/etc/passwd", true},
+		{"..", true},
+	}
+	for _, tt := range tests {
+		_, err := containedPath(root, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("containedPath(%q, %q) error = %v, wantErr %v", root, tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckSymlink(t *testing.T) {
+	root := "/tmp/release"
+	tests := []struct {
+		path    string
+		target  string
+		wantErr bool
+	}{
+		{root + "/link", "foo.txt", false},
+		{root + "/sub/link", "../foo.txt", false},
+		{root + "/link", "/etc/passwd", true},
+		{root + "/link", "../../etc/passwd", true},
+	}
+	for _, tt := range tests {
+		err := checkSymlink(root, tt.path, tt.target)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkSymlink(%q, %q, %q) error = %v, wantErr %v", root, tt.path, tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckHardlink(t *testing.T) {
+	root := "/tmp/release"
+	tests := []struct {
+		path    string
+		target  string
+		wantErr bool
+	}{
+		{root + "/link", "foo.txt", false},
+		{root + "/link", "../../etc/passwd", true},
+	}
+	for _, tt := range tests {
+		_, err := checkHardlink(root, tt.path, tt.target)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkHardlink(%q, %q, %q) error = %v, wantErr %v", root, tt.path, tt.target, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package unpacker
+
+import (
+	"io"
+	"regexp"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/mpolden/sfv"
+	"github.com/pkg/errors"
+)
+
+var sevenZipVolumeRE = regexp.MustCompile(`\.7z\.0*(\d+)$`)
+
+func isFirst7zVolume(name string) bool {
+	m := sevenZipVolumeRE.FindStringSubmatch(name)
+	if len(m) == 2 {
+		return m[1] == "1"
+	}
+	return extRE[Format7z].MatchString(name)
+}
+
+// sevenZipHandler implements FormatHandler for Format7z, using bodgit/sevenzip.
+// Multi-volume archives are joined automatically by that package as long as
+// name points to the first volume.
+type sevenZipHandler struct{}
+
+func (sevenZipHandler) FirstVolume(s *sfv.SFV) (string, bool) {
+	for _, c := range s.Checksums {
+		if isFirst7zVolume(c.Path) {
+			return c.Path, true
+		}
+	}
+	return "", false
+}
+
+func (sevenZipHandler) Open(name string) (archiveReader, error) {
+	r, err := sevenzip.OpenReader(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", name)
+	}
+	return &sevenZipReader{r: r}, nil
+}
+
+type sevenZipReader struct {
+	r       *sevenzip.ReadCloser
+	i       int
+	current io.ReadCloser
+}
+
+func (sr *sevenZipReader) Next() (entry, error) {
+	if sr.current != nil {
+		sr.current.Close()
+		sr.current = nil
+	}
+	if sr.i >= len(sr.r.File) {
+		return entry{}, io.EOF
+	}
+	f := sr.r.File[sr.i]
+	sr.i++
+	rc, err := f.Open()
+	if err != nil {
+		return entry{}, errors.Wrapf(err, "failed to open %s", f.Name)
+	}
+	sr.current = rc
+	return entry{
+		Name:    f.Name,
+		IsDir:   f.FileInfo().IsDir(),
+		Mode:    f.Mode(),
+		ModTime: f.Modified,
+	}, nil
+}
+
+func (sr *sevenZipReader) Read(p []byte) (int, error) {
+	if sr.current == nil {
+		return 0, io.EOF
+	}
+	return sr.current.Read(p)
+}
+
+func (sr *sevenZipReader) Close() error {
+	if sr.current != nil {
+		sr.current.Close()
+	}
+	return sr.r.Close()
+}
@@ -0,0 +1,81 @@
+package unpacker
+
+import (
+	"archive/tar"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/mpolden/sfv"
+	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz"
+)
+
+// decompress wraps r, the raw content of a compressed tar file, with the
+// decompression algorithm matching its Format.
+type decompress func(r io.Reader) (io.Reader, error)
+
+func gzipReader(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
+
+func bzip2Reader(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil }
+
+func xzReader(r io.Reader) (io.Reader, error) { return xz.NewReader(r) }
+
+// tarHandler implements FormatHandler for a compressed tar Format. Unlike RAR,
+// zip and 7z, a tar archive is always read as a single volume; split tar
+// archives are not supported.
+type tarHandler struct {
+	format     Format
+	decompress decompress
+}
+
+func (h tarHandler) FirstVolume(s *sfv.SFV) (string, bool) {
+	for _, c := range s.Checksums {
+		if extRE[h.format].MatchString(c.Path) {
+			return c.Path, true
+		}
+	}
+	return "", false
+}
+
+func (h tarHandler) Open(name string) (archiveReader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", name)
+	}
+	dr, err := h.decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "failed to decompress %s", name)
+	}
+	return &tarReader{f: f, r: tar.NewReader(dr)}, nil
+}
+
+type tarReader struct {
+	f *os.File
+	r *tar.Reader
+}
+
+func (tr *tarReader) Next() (entry, error) {
+	h, err := tr.r.Next()
+	if err != nil {
+		return entry{}, err
+	}
+	mode := h.FileInfo().Mode()
+	if h.Typeflag == tar.TypeSymlink {
+		mode |= os.ModeSymlink
+	}
+	return entry{
+		Name:     h.Name,
+		IsDir:    h.Typeflag == tar.TypeDir,
+		Mode:     mode,
+		ModTime:  h.ModTime,
+		Linkname: h.Linkname,
+		Hardlink: h.Typeflag == tar.TypeLink,
+	}, nil
+}
+
+func (tr *tarReader) Read(p []byte) (int, error) { return tr.r.Read(p) }
+
+func (tr *tarReader) Close() error { return tr.f.Close() }
@@ -0,0 +1,197 @@
+package unpacker
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mpolden/sfv"
+)
+
+func writeZipFixture(t *testing.T, path string, names []string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("payload")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type tarFixtureEntry struct {
+	name     string
+	linkname string
+	typeflag byte
+}
+
+func writeTarGzFixture(t *testing.T, path string, entries []tarFixtureEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		typeflag := e.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		h := &tar.Header{
+			Name:     e.name,
+			Linkname: e.linkname,
+			Typeflag: typeflag,
+			Mode:     0644,
+			Size:     0,
+		}
+		if typeflag == tar.TypeReg {
+			h.Size = int64(len("payload"))
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte("payload")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestUnpackZipRejectsPathTraversal builds a real zip fixture with an
+// entry that attempts to escape the extraction root, and drives it
+// through the actual zipHandler/unpack wiring end-to-end.
+func TestUnpackZipRejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "release.zip")
+	writeZipFixture(t, archive, []string{"good.txt", "../../../etc/passwd"})
+	u := &unpacker{Dir: dir, Name: "release.zip", Format: FormatZip}
+	if err := u.unpack(archive); err == nil {
+		t.Fatal("expected unpack to reject a path-traversal entry")
+	}
+}
+
+// TestUnpackTarGzRejectsSymlinkEscape builds a real tar.gz fixture
+// containing a symlink whose target escapes the extraction root, and
+// drives it through the actual tarHandler/unpack wiring end-to-end.
+func TestUnpackTarGzRejectsSymlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "release.tar.gz")
+	writeTarGzFixture(t, archive, []tarFixtureEntry{
+		{name: "good.txt"},
+		{name: "evil-link", linkname: "../../../etc/passwd", typeflag: tar.TypeSymlink},
+	})
+	u := &unpacker{Dir: dir, Name: "release.tar.gz", Format: FormatTarGz}
+	if err := u.unpack(archive); err == nil {
+		t.Fatal("expected unpack to reject a symlink escaping the extraction root")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "evil-link")); !os.IsNotExist(err) {
+		t.Fatal("symlink escaping the extraction root was created")
+	}
+}
+
+// TestUnpackTarGzRejectsHardlinkEscape builds a real tar.gz fixture
+// containing a hardlink whose target escapes the extraction root.
+func TestUnpackTarGzRejectsHardlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "release.tar.gz")
+	writeTarGzFixture(t, archive, []tarFixtureEntry{
+		{name: "evil-link", linkname: "../../../etc/passwd", typeflag: tar.TypeLink},
+	})
+	u := &unpacker{Dir: dir, Name: "release.tar.gz", Format: FormatTarGz}
+	if err := u.unpack(archive); err == nil {
+		t.Fatal("expected unpack to reject a hardlink escaping the extraction root")
+	}
+}
+
+// fakeEntryReader drives unpack() with a fixed list of entries. It backs
+// TestUnpackRejectsTraversalForEveryFormat, standing in for formats (RAR,
+// 7z) that this toolchain has no fixture-writing library for: rardecode
+// and bodgit/sevenzip are read-only, so a malicious RAR/7z fixture can't
+// be generated here the way the zip/tar ones above are. This still
+// exercises the real unpack()/containedPath wiring for every Format, just
+// without going through a real per-format decoder.
+type fakeEntryReader struct {
+	entries []entry
+	i       int
+}
+
+func (r *fakeEntryReader) Next() (entry, error) {
+	if r.i >= len(r.entries) {
+		return entry{}, io.EOF
+	}
+	e := r.entries[r.i]
+	r.i++
+	return e, nil
+}
+
+func (r *fakeEntryReader) Read(p []byte) (int, error) { return 0, io.EOF }
+
+func (r *fakeEntryReader) Close() error { return nil }
+
+type fakeFormatHandler struct{ entries []entry }
+
+func (h fakeFormatHandler) FirstVolume(*sfv.SFV) (string, bool) { return "fixture", true }
+
+func (h fakeFormatHandler) Open(name string) (archiveReader, error) {
+	return &fakeEntryReader{entries: h.entries}, nil
+}
+
+func TestUnpackRejectsTraversalForEveryFormat(t *testing.T) {
+	malicious := []entry{{Name: "../../../etc/passwd"}}
+	for format := range handlers {
+		t.Run(formatName(format), func(t *testing.T) {
+			orig := handlers[format]
+			handlers[format] = fakeFormatHandler{entries: malicious}
+			defer func() { handlers[format] = orig }()
+
+			dir := t.TempDir()
+			u := &unpacker{Dir: dir, Name: "fixture", Format: format}
+			if err := u.unpack("fixture"); err == nil {
+				t.Fatalf("expected unpack to reject a path-traversal entry for format %v", format)
+			}
+		})
+	}
+}
+
+func formatName(f Format) string {
+	switch f {
+	case FormatRAR:
+		return "rar"
+	case FormatZip:
+		return "zip"
+	case Format7z:
+		return "7z"
+	case FormatTarGz:
+		return "tar.gz"
+	case FormatTarBz2:
+		return "tar.bz2"
+	case FormatTarXz:
+		return "tar.xz"
+	default:
+		return "unknown"
+	}
+}
@@ -5,83 +5,67 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 
 	"github.com/mpolden/sfv"
-	"github.com/nwaples/rardecode"
 	"github.com/pkg/errors"
 )
 
-var rarPartRE = regexp.MustCompile(`\.part0*(\d+)\.rar$`)
-
 type unpacker struct {
-	SFV  *sfv.SFV
-	Dir  string
-	Name string
+	SFV    *sfv.SFV
+	Dir    string
+	Name   string
+	Format Format
 }
 
 func New(dir string) (*unpacker, error) {
-	sfv, err := sfv.Find(dir)
+	s, err := sfv.Find(dir)
 	if err != nil {
 		return nil, err
 	}
-	rar, err := findFirstRAR(sfv)
+	format, _, name, err := detectFormat(s)
 	if err != nil {
 		return nil, err
 	}
 	return &unpacker{
-		SFV:  sfv,
-		Dir:  dir,
-		Name: rar,
+		SFV:    s,
+		Dir:    dir,
+		Name:   name,
+		Format: format,
 	}, nil
 }
 
-func isRAR(name string) bool { return filepath.Ext(name) == ".rar" }
-
-func isFirstRAR(name string) bool {
-	m := rarPartRE.FindStringSubmatch(name)
-	if len(m) == 2 {
-		return m[1] == "1"
-	}
-	return isRAR(name)
-}
-
-func findFirstRAR(s *sfv.SFV) (string, error) {
-	for _, c := range s.Checksums {
-		if isFirstRAR(c.Path) {
-			return c.Path, nil
-		}
-	}
-	return "", errors.Errorf("no rar file found in %s", s.Path)
-}
-
-func chtimes(name string, header *rardecode.FileHeader) error {
-	if header.ModificationTime.IsZero() {
+func chtimes(name string, e entry) error {
+	if e.ModTime.IsZero() {
 		return nil
 	}
-	return os.Chtimes(name, header.ModificationTime, header.ModificationTime)
+	return os.Chtimes(name, e.ModTime, e.ModTime)
 }
 
 func (u *unpacker) unpack(name string) error {
-	r, err := rardecode.OpenReader(name, "")
+	h := handlers[u.Format]
+	r, err := h.Open(name)
 	if err != nil {
-		return errors.Wrapf(err, "failed to open %s", name)
+		return err
 	}
+	defer r.Close()
 	for {
-		header, err := r.Next()
+		e, err := r.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return err
 		}
-		name := filepath.Join(u.Dir, header.Name)
+		name, err := containedPath(u.Dir, e.Name)
+		if err != nil {
+			return err
+		}
 		// If entry is a directory, create it and set correct ctime
-		if header.IsDir {
+		if e.IsDir {
 			if err := os.MkdirAll(name, 0755); err != nil {
 				return err
 			}
-			if err := chtimes(name, header); err != nil {
+			if err := chtimes(name, e); err != nil {
 				return err
 			}
 			continue
@@ -91,9 +75,28 @@ func (u *unpacker) unpack(name string) error {
 		if err := os.MkdirAll(parent, 0755); err != nil {
 			return err
 		}
-		if err := chtimes(parent, header); err != nil {
+		if err := chtimes(parent, e); err != nil {
 			return err
 		}
+		if e.Mode&os.ModeSymlink != 0 {
+			if err := checkSymlink(u.Dir, name, e.Linkname); err != nil {
+				return err
+			}
+			if err := os.Symlink(e.Linkname, name); err != nil {
+				return err
+			}
+			continue
+		}
+		if e.Hardlink {
+			target, err := checkHardlink(u.Dir, name, e.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := os.Link(target, name); err != nil {
+				return err
+			}
+			continue
+		}
 		// Unpack file
 		f, err := os.Create(name)
 		if err != nil {
@@ -105,12 +108,15 @@ func (u *unpacker) unpack(name string) error {
 		if err := f.Close(); err != nil {
 			return err
 		}
+		if err := os.Chmod(name, e.Mode.Perm()); err != nil {
+			return err
+		}
 		// Set correct ctime of unpacked file
-		if err := chtimes(name, header); err != nil {
+		if err := chtimes(name, e); err != nil {
 			return err
 		}
-		// Unpack recursively if unpacked file is also a RAR
-		if isRAR(name) {
+		// Unpack recursively if the unpacked file is itself a RAR archive
+		if u.Format == FormatRAR && isRAR(name) {
 			if err := u.unpack(name); err != nil {
 				return err
 			}
@@ -138,37 +144,6 @@ func (u *unpacker) fileCount() (int, int) {
 	return exists, len(u.SFV.Checksums)
 }
 
-func (u *unpacker) verify() error {
-	for _, c := range u.SFV.Checksums {
-		ok, err := c.Verify()
-		if err != nil {
-			return err
-		}
-		if !ok {
-			return errors.Errorf("%s: failed checksum: %s", u.SFV.Path, c.Filename)
-		}
-	}
-	return nil
-}
-
-func (u *unpacker) Run(removeRARs bool) error {
-	if exists, total := u.fileCount(); exists != total {
-		return errors.Errorf("%s is incomplete: %d/%d files", u.Dir, exists, total)
-	}
-	if err := u.verify(); err != nil {
-		return errors.Wrapf(err, "verification of %s failed", u.Dir)
-	}
-	if err := u.unpack(u.Name); err != nil {
-		return errors.Wrapf(err, "unpacking %s failed", u.Dir)
-	}
-	if removeRARs {
-		if err := u.remove(); err != nil {
-			return errors.Wrapf(err, "cleaning up %s failed", u.Dir)
-		}
-	}
-	return nil
-}
-
 func postProcess(u *unpacker, command string) error {
 	if command == "" {
 		return nil
@@ -189,17 +164,3 @@ func postProcess(u *unpacker, command string) error {
 	}
 	return nil
 }
-
-func OnFile(name, postCommand string, remove bool) error {
-	u, err := New(filepath.Dir(name))
-	if err != nil {
-		return errors.Wrap(err, "failed to initialize unpacker")
-	}
-	if err := u.Run(remove); err != nil {
-		return err
-	}
-	if err := postProcess(u, postCommand); err != nil {
-		return errors.Wrap(err, "post-process command failed")
-	}
-	return nil
-}
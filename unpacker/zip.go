@@ -0,0 +1,83 @@
+package unpacker
+
+import (
+	"archive/zip"
+	"io"
+	"regexp"
+
+	"github.com/mpolden/sfv"
+	"github.com/pkg/errors"
+)
+
+var zipVolumeRE = regexp.MustCompile(`\.zip\.0*(\d+)$`)
+
+func isFirstZipVolume(name string) bool {
+	m := zipVolumeRE.FindStringSubmatch(name)
+	if len(m) == 2 {
+		return m[1] == "1"
+	}
+	return extRE[FormatZip].MatchString(name)
+}
+
+// zipHandler implements FormatHandler for FormatZip, using archive/zip.
+type zipHandler struct{}
+
+func (zipHandler) FirstVolume(s *sfv.SFV) (string, bool) {
+	for _, c := range s.Checksums {
+		if isFirstZipVolume(c.Path) {
+			return c.Path, true
+		}
+	}
+	return "", false
+}
+
+func (zipHandler) Open(name string) (archiveReader, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", name)
+	}
+	return &zipReader{r: r}, nil
+}
+
+type zipReader struct {
+	r       *zip.ReadCloser
+	i       int
+	current io.ReadCloser
+}
+
+func (zr *zipReader) Next() (entry, error) {
+	if zr.current != nil {
+		zr.current.Close()
+		zr.current = nil
+	}
+	if zr.i >= len(zr.r.File) {
+		return entry{}, io.EOF
+	}
+	f := zr.r.File[zr.i]
+	zr.i++
+	rc, err := f.Open()
+	if err != nil {
+		return entry{}, errors.Wrapf(err, "failed to open %s", f.Name)
+	}
+	zr.current = rc
+	return entry{
+		Name:    f.Name,
+		IsDir:   f.FileInfo().IsDir(),
+		Mode:    f.Mode(),
+		ModTime: f.Modified,
+	}, nil
+}
+
+func (zr *zipReader) Read(p []byte) (int, error) {
+	if zr.current == nil {
+		return 0, io.EOF
+	}
+	return zr.current.Read(p)
+}
+
+func (zr *zipReader) Close() error {
+	if zr.current != nil {
+		zr.current.Close()
+	}
+	return zr.r.Close()
+}
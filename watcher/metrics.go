@@ -0,0 +1,81 @@
+package watcher
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	eventsReceivedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "unp",
+		Name:      "events_received_total",
+		Help:      "Total number of filesystem events received.",
+	})
+	unpacksSucceededTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "unp",
+		Name:      "unpacks_succeeded_total",
+		Help:      "Total number of filesystem events handled without error. This includes events that did not lead to an unpack, such as those for an incomplete or already processed release.",
+	})
+	unpacksFailedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "unp",
+		Name:      "unpacks_failed_total",
+		Help:      "Total number of filesystem events whose handling returned an error. This includes events skipped for not matching a watched path's rules.",
+	})
+	verifyFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "unp",
+		Name:      "verify_failures_total",
+		Help:      "Total number of failed CRC verifications of single files. A file that fails is verified, and counted, again on each later event for it.",
+	})
+	unpackDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "unp",
+		Name:      "unpack_duration_seconds",
+		Help:      "Time taken to handle a single filesystem event, from when a worker starts handling it to completion.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	archiveSizeBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "unp",
+		Name:      "archive_size_bytes",
+		Help:      "Size of unpacked archive files.",
+		Buckets:   prometheus.ExponentialBuckets(1<<20, 4, 8), // 1 MiB .. 16 GiB
+	})
+	jobsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "unp",
+		Name:      "jobs_in_flight",
+		Help:      "Number of unpack jobs currently queued or running.",
+	})
+	cachedVerifiedFiles = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "unp",
+		Name:      "cached_verified_files",
+		Help:      "Number of files the configured OnFile handler currently has recorded as verified.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		eventsReceivedTotal,
+		unpacksSucceededTotal,
+		unpacksFailedTotal,
+		verifyFailuresTotal,
+		unpackDurationSeconds,
+		archiveSizeBytes,
+		jobsInFlight,
+		cachedVerifiedFiles,
+	)
+}
+
+// MetricsReporter is implemented by OnFile handlers that can report
+// additional state for inclusion in the watcher's /metrics output.
+type MetricsReporter interface {
+	// CachedVerifiedFiles returns the number of files currently recorded
+	// as verified in the handler's cache.
+	CachedVerifiedFiles() int
+}
+
+// ObserveArchiveSize records the size of an archive that was unpacked. It
+// is exported so that OnFile handlers, which perform the actual
+// extraction, can report archive sizes without importing this package's
+// internals.
+func ObserveArchiveSize(bytes int64) { archiveSizeBytes.Observe(float64(bytes)) }
+
+// ObserveVerifyFailure records that a single file failed CRC verification.
+// It is exported for the same reason as ObserveArchiveSize.
+func ObserveVerifyFailure() { verifyFailuresTotal.Inc() }
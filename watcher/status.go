@@ -0,0 +1,63 @@
+package watcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const maxRecentErrors = 20
+
+// status tracks lightweight operational state exposed via /status,
+// alongside the Prometheus metrics exposed via /metrics.
+type status struct {
+	mu         sync.Mutex
+	lastEvents map[string]time.Time
+	errors     []string
+}
+
+func newStatus() *status {
+	return &status{lastEvents: make(map[string]time.Time)}
+}
+
+func (s *status) recordEvent(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastEvents[path] = time.Now()
+}
+
+func (s *status) recordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, err.Error())
+	if len(s.errors) > maxRecentErrors {
+		s.errors = s.errors[len(s.errors)-maxRecentErrors:]
+	}
+}
+
+type statusResponse struct {
+	Paths      []string             `json:"paths"`
+	LastEvents map[string]time.Time `json:"last_events"`
+	Errors     []string             `json:"recent_errors"`
+}
+
+func (w *watcher) serveStatus(rw http.ResponseWriter, r *http.Request) {
+	paths := make([]string, 0, len(w.config.Paths))
+	for _, p := range w.config.Paths {
+		paths = append(paths, p.Name)
+	}
+	w.status.mu.Lock()
+	data, err := json.Marshal(statusResponse{
+		Paths:      paths,
+		LastEvents: w.status.lastEvents,
+		Errors:     w.status.errors,
+	})
+	w.status.mu.Unlock()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(data)
+}
@@ -1,10 +1,13 @@
 package watcher
 
 import (
+	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
 	"sync"
 	"syscall"
+	"time"
 
 	"path/filepath"
 
@@ -12,20 +15,43 @@ import (
 
 	"github.com/mpolden/unp/pathutil"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rjeczalik/notify"
 )
 
 type OnFile func(string, string, bool) error
 
+// Stopper is implemented by OnFile handlers that own background resources,
+// such as caches or worker pools, needing a graceful shutdown when the
+// watcher stops.
+type Stopper interface {
+	Stop()
+}
+
+// event is a single unit of dispatchable work: a file that matched a
+// watched path and is ready to be handled by a worker.
+type event struct {
+	path string
+}
+
 type watcher struct {
-	config Config
-	onFile OnFile
-	events chan notify.EventInfo
-	signal chan os.Signal
-	done   chan bool
-	log    *log.Logger
-	mu     sync.Mutex
-	wg     sync.WaitGroup
+	config     Config
+	onFile     OnFile
+	stopper    Stopper
+	reporter   MetricsReporter
+	events     chan notify.EventInfo
+	jobs       chan event
+	signal     chan os.Signal
+	done       chan bool
+	log        *log.Logger
+	workers    int
+	status     *status
+	httpServer *http.Server
+	mu         sync.Mutex
+	activeMu   sync.Mutex
+	active     map[string]struct{}
+	pending    map[string]string
+	wg         sync.WaitGroup
 }
 
 func (w *watcher) handle(name string) error {
@@ -81,9 +107,10 @@ func (w *watcher) rescan() {
 			if info == nil || !info.Mode().IsRegular() {
 				return nil
 			}
-			if err := w.handle(path); err != nil {
-				w.log.Printf("Skipping event: %s", err)
-			}
+			// Route through the same dispatch/jobs path as readEvent so a
+			// rescan can never race a pool worker already unpacking path's
+			// directory.
+			w.dispatch(path)
 			return nil
 		})
 		if err != nil {
@@ -115,23 +142,102 @@ func (w *watcher) readSignal() {
 	}
 }
 
+// release clears the in-flight marker set for the directory containing
+// path, unless a later event for that directory was coalesced into
+// w.pending while it was in flight, in which case that event is enqueued
+// in its place and the directory stays marked active.
+func (w *watcher) release(path string) {
+	dir := filepath.Dir(path)
+	w.activeMu.Lock()
+	next, ok := w.pending[dir]
+	if ok {
+		delete(w.pending, dir)
+	} else {
+		delete(w.active, dir)
+	}
+	w.activeMu.Unlock()
+	if ok {
+		w.enqueue(next)
+	}
+}
+
+// enqueue sends path to the worker pool, guarding the send with w.done so
+// that a caller blocked here (e.g. rescan, invoked synchronously from
+// readSignal) can never prevent the watcher from shutting down.
+func (w *watcher) enqueue(path string) {
+	jobsInFlight.Inc()
+	select {
+	case w.jobs <- event{path: path}:
+	case <-w.done:
+		jobsInFlight.Dec()
+		w.release(path)
+	}
+}
+
+// dispatch queues path for handling by the worker pool, coalescing with
+// any unpack already in flight for its containing directory: if one is
+// in flight, path is recorded in w.pending and picked up by release once
+// that job completes, rather than being dropped. It is used by both
+// readEvent and rescan, so that a signal-triggered rescan can never run
+// concurrently with a pool worker already unpacking the same directory.
+func (w *watcher) dispatch(path string) {
+	eventsReceivedTotal.Inc()
+	w.status.recordEvent(path)
+	dir := filepath.Dir(path)
+	w.activeMu.Lock()
+	if _, ok := w.active[dir]; ok {
+		w.pending[dir] = path
+		w.activeMu.Unlock()
+		return
+	}
+	w.active[dir] = struct{}{}
+	w.activeMu.Unlock()
+	w.enqueue(path)
+}
+
+// readEvent is the producer: it dispatches matched filesystem events to
+// w.jobs, coalescing events for a directory that already has an unpack in
+// flight.
 func (w *watcher) readEvent() {
 	for {
 		select {
 		case <-w.done:
 			return
 		case ev := <-w.events:
-			w.mu.Lock()
-			if err := w.handle(ev.Path()); err != nil {
+			w.dispatch(ev.Path())
+		}
+	}
+}
+
+// work is run by each worker in the pool, handling jobs until the watcher
+// is stopped.
+func (w *watcher) work() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev := <-w.jobs:
+			start := time.Now()
+			err := w.handle(ev.path)
+			unpackDurationSeconds.Observe(time.Since(start).Seconds())
+			if err != nil {
 				w.log.Printf("Skipping event: %s", err)
+				w.status.recordError(err)
+				unpacksFailedTotal.Inc()
+			} else {
+				unpacksSucceededTotal.Inc()
 			}
-			w.mu.Unlock()
+			if w.reporter != nil {
+				cachedVerifiedFiles.Set(float64(w.reporter.CachedVerifiedFiles()))
+			}
+			w.release(ev.path)
+			jobsInFlight.Dec()
 		}
 	}
 }
 
 func (w *watcher) goServe() {
-	w.wg.Add(2)
+	w.wg.Add(2 + w.workers)
 	go func() {
 		defer w.wg.Done()
 		w.readSignal()
@@ -140,6 +246,21 @@ func (w *watcher) goServe() {
 		defer w.wg.Done()
 		w.readEvent()
 	}()
+	for i := 0; i < w.workers; i++ {
+		go func() {
+			defer w.wg.Done()
+			w.work()
+		}()
+	}
+	if w.httpServer != nil {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			if err := w.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				w.log.Printf("Status server failed: %s", err)
+			}
+		}()
+	}
 }
 
 func (w *watcher) Start() {
@@ -150,22 +271,65 @@ func (w *watcher) Start() {
 
 func (w *watcher) Stop() {
 	notify.Stop(w.events)
-	w.done <- true
-	w.done <- true
+	if w.stopper != nil {
+		w.stopper.Stop()
+	}
+	if w.httpServer != nil {
+		w.httpServer.Close()
+	}
+	// Close rather than send one value per goroutine: enqueue also receives
+	// from w.done, and must not take a value another goroutine waits for.
+	close(w.done)
+}
+
+// workerCount returns the configured number of unpack workers, defaulting
+// to runtime.NumCPU() when unset.
+func workerCount(cfg Config) int {
+	if cfg.Workers > 0 {
+		return cfg.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// newHTTPServer returns the embedded status/metrics server for cfg, or nil
+// if cfg.MetricsAddr is unset. The server is disabled by default.
+func newHTTPServer(cfg Config, w *watcher) *http.Server {
+	if cfg.MetricsAddr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/status", w.serveStatus)
+	return &http.Server{Addr: cfg.MetricsAddr, Handler: mux}
 }
 
-func New(cfg Config, onFile OnFile, log *log.Logger) *watcher {
+// New creates a new watcher using cfg, dispatching matched files to onFile.
+// If onFile owns background resources that need a graceful shutdown, pass
+// it as stopper as well so that Stop can reach it; stopper may be nil. If
+// onFile can report additional state for /metrics, pass it as reporter;
+// reporter may also be nil.
+func New(cfg Config, onFile OnFile, stopper Stopper, reporter MetricsReporter, log *log.Logger) *watcher {
 	// Buffer events so that we don't miss any
 	events := make(chan notify.EventInfo, cfg.BufferSize)
+	workers := workerCount(cfg)
 	sig := make(chan os.Signal, 1)
 	done := make(chan bool, 1)
 	signal.Notify(sig)
-	return &watcher{
-		config: cfg,
-		events: events,
-		log:    log,
-		onFile: onFile,
-		signal: sig,
-		done:   done,
+	w := &watcher{
+		config:   cfg,
+		events:   events,
+		jobs:     make(chan event, cfg.BufferSize),
+		log:      log,
+		onFile:   onFile,
+		stopper:  stopper,
+		reporter: reporter,
+		workers:  workers,
+		status:   newStatus(),
+		signal:   sig,
+		done:     done,
+		active:   make(map[string]struct{}),
+		pending:  make(map[string]string),
 	}
+	w.httpServer = newHTTPServer(cfg, w)
+	return w
 }
@@ -0,0 +1,86 @@
+package watcher
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestWatcher() *watcher {
+	return &watcher{
+		jobs:    make(chan event, 1),
+		done:    make(chan bool, 1),
+		status:  newStatus(),
+		active:  make(map[string]struct{}),
+		pending: make(map[string]string),
+	}
+}
+
+// TestDispatchCoalescesPendingEvents verifies that an event for a
+// directory that already has an unpack in flight is queued in w.pending
+// rather than dropped, and that release picks it up once the in-flight
+// job completes.
+func TestDispatchCoalescesPendingEvents(t *testing.T) {
+	w := newTestWatcher()
+
+	w.dispatch("/release/a.r00")
+	select {
+	case ev := <-w.jobs:
+		if ev.path != "/release/a.r00" {
+			t.Fatalf("got path %q, want %q", ev.path, "/release/a.r00")
+		}
+	default:
+		t.Fatal("expected first dispatch to enqueue a job")
+	}
+
+	// A second event for the same directory arrives while the first is
+	// still in flight (its job has been received but not yet released).
+	w.dispatch("/release/a.r01")
+	select {
+	case ev := <-w.jobs:
+		t.Fatalf("expected coalesced event to be queued, not enqueued immediately, got %q", ev.path)
+	default:
+	}
+	if got, ok := w.pending["/release"]; !ok || got != "/release/a.r01" {
+		t.Fatalf("expected /release/a.r01 to be recorded as pending, got %q, ok=%v", got, ok)
+	}
+
+	// Releasing the in-flight job should enqueue the coalesced event
+	// instead of clearing the directory as idle.
+	w.release("/release/a.r00")
+	if _, ok := w.pending["/release"]; ok {
+		t.Fatal("expected pending entry to be cleared after release")
+	}
+	select {
+	case ev := <-w.jobs:
+		if ev.path != "/release/a.r01" {
+			t.Fatalf("got path %q, want %q", ev.path, "/release/a.r01")
+		}
+	default:
+		t.Fatal("expected release to enqueue the coalesced event")
+	}
+
+	// With no pending event, releasing clears the directory as idle.
+	w.release("/release/a.r01")
+	if _, ok := w.active["/release"]; ok {
+		t.Fatal("expected directory to be cleared from active once idle")
+	}
+}
+
+// TestEnqueueDoesNotBlockOnDone verifies that enqueue never blocks
+// indefinitely on a full jobs channel once the watcher is shutting down.
+func TestEnqueueDoesNotBlockOnDone(t *testing.T) {
+	w := newTestWatcher()
+	w.jobs <- event{path: "/release/filler"} // fill the buffered channel
+	w.done <- true
+
+	done := make(chan struct{})
+	go func() {
+		w.enqueue("/release/a.r00")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("enqueue blocked instead of returning once w.done fired")
+	}
+}